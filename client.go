@@ -0,0 +1,388 @@
+package rawhttp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultClient is the *Client used by the package-level Do function.
+var DefaultClient = NewClient()
+
+// idleConn is a connection sitting in a Client's pool, along with the
+// time it was returned so stale connections can be expired.
+type idleConn struct {
+	conn      net.Conn
+	idleSince time.Time
+}
+
+// Client keeps a pool of idle connections so that repeated requests to
+// the same host don't each pay for a fresh TCP/TLS handshake.
+type Client struct {
+	// MaxIdleConnsPerHost is the maximum number of idle connections to
+	// keep around per host. The zero value means 2.
+	MaxIdleConnsPerHost int
+
+	// IdleTimeout is how long an idle connection can sit in the pool
+	// before it's treated as stale and closed instead of reused. The
+	// zero value means 90 seconds.
+	IdleTimeout time.Duration
+
+	// DialTimeout is the timeout used when dialing a new connection, for
+	// requests that don't set their own Requester.GetTimeout(). The zero
+	// value means 30 seconds.
+	DialTimeout time.Duration
+
+	// TLSTimeout is the timeout used for the TLS handshake on new TLS
+	// connections. The zero value means DialTimeout (or the request's
+	// own timeout, if it has one).
+	TLSTimeout time.Duration
+
+	// KeepAlive is the keep-alive period set on the underlying TCP
+	// connection. The zero value means 30 seconds.
+	KeepAlive time.Duration
+
+	// Jar, if set, is used to inject Cookie headers into outgoing
+	// requests and to store Set-Cookie headers from responses. It's
+	// only consulted for requests made with *Request, since a
+	// RawRequest has no structured URL to match cookies against.
+	Jar CookieJar
+
+	// TLSConfig, if set, is used for TLS connections made by requests
+	// that don't provide their own override via Request.TLSConfig. A
+	// nil TLSConfig means the system root pool with certificate
+	// verification disabled.
+	TLSConfig *tls.Config
+
+	// Proxy, if set, is used for requests that don't provide their own
+	// override via Request.Proxy. Supported schemes are "http", "https"
+	// and "socks5".
+	Proxy *url.URL
+
+	mu   sync.Mutex
+	idle map[string][]*idleConn
+}
+
+// NewClient returns a *Client with sane default settings.
+func NewClient() *Client {
+	return &Client{
+		idle: make(map[string][]*idleConn),
+	}
+}
+
+// Do performs req using c's connection pool, returning a *Response and
+// any error that occured. If the request/response pair indicates
+// keep-alive, the connection is returned to the pool for reuse;
+// otherwise it's closed.
+func (c *Client) Do(req Requester) (*Response, error) {
+	key := c.connKey(req)
+
+	wire := req.String()
+
+	u := c.jarURL(req)
+	if u != nil {
+		if cookies := c.Jar.Cookies(u); len(cookies) > 0 {
+			// Build the Cookie header onto a copy of the request rather
+			// than calling AddHeader on req itself: callers are expected
+			// to reuse the same *Request across a session, and mutating
+			// it here would pile up another Cookie header on every call.
+			wire = withCookies(req.(*Request), cookies)
+		}
+	}
+
+	// A *Request always terminates its own headers with a blank line (in
+	// its own EOL, which may not be "\r\n"), so a blanket CRLF check here
+	// would misfire on one built with a non-standard EOL and corrupt the
+	// exact wire bytes the caller constructed. Only RawRequest - whose
+	// Request string is taken on faith to be a complete, well-formed
+	// message - gets padded if its author forgot the trailing blank line.
+	if _, ok := req.(RawRequest); ok && !strings.Contains(wire, "\r\n\r\n") {
+		wire += "\r\n"
+	}
+
+	conn := c.popIdleConn(key)
+	if conn == nil {
+		var err error
+		conn, err = c.dial(req)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := fmt.Fprint(conn, wire); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	resp, err := newResponse(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if u != nil {
+		if cookies := parseSetCookies(resp.Headers()); len(cookies) > 0 {
+			c.Jar.SetCookies(u, cookies)
+		}
+	}
+
+	if keepAliveOK(req, resp) {
+		c.pushIdleConn(key, conn)
+	} else {
+		conn.Close()
+	}
+
+	return resp, nil
+}
+
+// jarURL returns the *url.URL to match cookies against for req, or nil
+// if c has no Jar configured or req isn't a *Request.
+func (c *Client) jarURL(req Requester) *url.URL {
+	if c.Jar == nil {
+		return nil
+	}
+
+	r, ok := req.(*Request)
+	if !ok {
+		return nil
+	}
+
+	u, err := url.Parse(r.URL())
+	if err != nil {
+		return nil
+	}
+
+	return u
+}
+
+// connKey returns the pool key for req: its host plus whether the
+// connection is over TLS, since a plaintext and TLS connection to the
+// same host:port are never interchangeable.
+func (c *Client) connKey(req Requester) string {
+	return fmt.Sprintf("%s|%v", req.Host(), req.IsTLS())
+}
+
+// dial opens a fresh connection for req, routing through a proxy if one
+// applies and wrapping the connection in TLS if req.IsTLS() returns
+// true.
+func (c *Client) dial(req Requester) (net.Conn, error) {
+	conn, err := c.dialTransport(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.IsTLS() {
+		return conn, nil
+	}
+
+	conf, err := c.tlsConfigFor(req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	tlsConn := tls.Client(conn, conf)
+
+	tlsConn.SetDeadline(time.Now().Add(c.tlsTimeout(req)))
+	if err := tlsConn.Handshake(); err != nil {
+		tlsConn.Close()
+		return nil, err
+	}
+	tlsConn.SetDeadline(time.Time{})
+
+	return tlsConn, nil
+}
+
+// dialTransport opens the underlying connection for req: a direct dial
+// to req.Host(), or a tunnel through a proxy if one is configured for
+// the request or the Client.
+func (c *Client) dialTransport(req Requester) (net.Conn, error) {
+	timeout := c.dialTimeout(req)
+
+	proxy := c.proxyFor(req)
+	if proxy == nil {
+		d := &net.Dialer{Timeout: timeout, KeepAlive: c.keepAlive()}
+		return d.Dial("tcp", req.Host())
+	}
+
+	return c.dialProxy(proxy, req.Host(), timeout)
+}
+
+// proxyFor returns the proxy to use for req: its own override if it has
+// one, otherwise the Client's.
+func (c *Client) proxyFor(req Requester) *url.URL {
+	if p, ok := req.(interface{ GetProxy() *url.URL }); ok {
+		if proxy := p.GetProxy(); proxy != nil {
+			return proxy
+		}
+	}
+	return c.Proxy
+}
+
+// tlsConfigFor returns the *tls.Config to use for req: its own override
+// if it has one, otherwise the Client's, otherwise the package default
+// of a system root pool with certificate verification disabled (this
+// library is meant for doing stupid stuff, so skipping verification by
+// default is actually the right thing to do).
+func (c *Client) tlsConfigFor(req Requester) (*tls.Config, error) {
+	if t, ok := req.(interface{ GetTLSConfig() *tls.Config }); ok {
+		if conf := t.GetTLSConfig(); conf != nil {
+			return conf, nil
+		}
+	}
+
+	if c.TLSConfig != nil {
+		return c.TLSConfig, nil
+	}
+
+	roots, err := x509.SystemCertPool()
+	if err != nil {
+		return nil, err
+	}
+
+	return &tls.Config{RootCAs: roots, InsecureSkipVerify: true}, nil
+}
+
+// popIdleConn returns an idle connection for key, discarding any that
+// have sat in the pool longer than IdleTimeout. It returns nil if there
+// are none to reuse.
+func (c *Client) popIdleConn(key string) net.Conn {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	conns := c.idle[key]
+	for len(conns) > 0 {
+		ic := conns[len(conns)-1]
+		conns = conns[:len(conns)-1]
+		c.idle[key] = conns
+
+		if time.Since(ic.idleSince) > c.idleTimeout() {
+			ic.conn.Close()
+			continue
+		}
+
+		return ic.conn
+	}
+
+	return nil
+}
+
+// pushIdleConn returns conn to the pool for key, closing it instead if
+// the pool for that key is already at MaxIdleConnsPerHost.
+func (c *Client) pushIdleConn(key string, conn net.Conn) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.idle[key]) >= c.maxIdleConnsPerHost() {
+		conn.Close()
+		return
+	}
+
+	c.idle[key] = append(c.idle[key], &idleConn{conn: conn, idleSince: time.Now()})
+}
+
+func (c *Client) maxIdleConnsPerHost() int {
+	if c.MaxIdleConnsPerHost > 0 {
+		return c.MaxIdleConnsPerHost
+	}
+	return 2
+}
+
+func (c *Client) idleTimeout() time.Duration {
+	if c.IdleTimeout > 0 {
+		return c.IdleTimeout
+	}
+	return 90 * time.Second
+}
+
+// dialTimeout returns the timeout to use when dialing req: req's own
+// GetTimeout() takes priority (this is how callers override it per
+// request), falling back to c.DialTimeout, then a default of 30 seconds.
+func (c *Client) dialTimeout(req Requester) time.Duration {
+	if t := req.GetTimeout(); t > 0 {
+		return t
+	}
+	if c.DialTimeout > 0 {
+		return c.DialTimeout
+	}
+	return 30 * time.Second
+}
+
+// tlsTimeout returns the timeout to use for req's TLS handshake:
+// c.TLSTimeout if set, otherwise the same timeout dialTimeout would use.
+func (c *Client) tlsTimeout(req Requester) time.Duration {
+	if c.TLSTimeout > 0 {
+		return c.TLSTimeout
+	}
+	return c.dialTimeout(req)
+}
+
+func (c *Client) keepAlive() time.Duration {
+	if c.KeepAlive > 0 {
+		return c.KeepAlive
+	}
+	return 30 * time.Second
+}
+
+// keepAliveOK reports whether the connection used for req/resp can be
+// reused for a later request.
+func keepAliveOK(req Requester, resp *Response) bool {
+	return !requestWantsClose(req) && !responseWantsClose(resp)
+}
+
+// requestWantsClose reports whether req is HTTP/1.0 (or otherwise not
+// HTTP/1.1) or carries an explicit "Connection: close" header, either of
+// which rules out reusing the connection.
+func requestWantsClose(req Requester) bool {
+	// Split on "\n" and trim any "\r" so that a *Request built with a
+	// non-standard EOL still has its headers inspected.
+	lines := strings.Split(req.String(), "\n")
+
+	if len(lines) == 0 || !strings.Contains(lines[0], "HTTP/1.1") {
+		return true
+	}
+
+	for _, line := range lines[1:] {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			// end of headers
+			break
+		}
+
+		p := strings.SplitN(line, ":", 2)
+		if len(p) != 2 {
+			continue
+		}
+
+		if strings.EqualFold(strings.TrimSpace(p[0]), "Connection") && strings.EqualFold(strings.TrimSpace(p[1]), "close") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// responseWantsClose reports whether resp is HTTP/1.0 (or otherwise not
+// HTTP/1.1), carries an explicit "Connection: close" header, or doesn't
+// give us a way to know where the body ends without closing the
+// connection (i.e. no Content-Length and not chunked).
+func responseWantsClose(resp *Response) bool {
+	if !strings.Contains(resp.StatusLine(), "HTTP/1.1") {
+		return true
+	}
+
+	if strings.EqualFold(resp.Header("Connection"), "close") {
+		return true
+	}
+
+	if resp.Header("Content-Length") == "" && !isChunked(resp.Header("Transfer-Encoding")) {
+		return true
+	}
+
+	return false
+}
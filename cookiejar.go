@@ -0,0 +1,236 @@
+package rawhttp
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A Cookie is a single HTTP cookie, either parsed from a Set-Cookie
+// header or built to be sent in one.
+type Cookie struct {
+	Name    string
+	Value   string
+	Domain  string
+	Path    string
+	Expires time.Time
+	Secure  bool
+}
+
+// CookieJar stores cookies received from responses and returns the
+// ones that should be sent on subsequent requests.
+type CookieJar interface {
+	// SetCookies stores cookies that came from a response to u.
+	SetCookies(u *url.URL, cookies []*Cookie)
+
+	// Cookies returns the cookies that should be sent in a request to u.
+	Cookies(u *url.URL) []*Cookie
+}
+
+// MemoryCookieJar is a CookieJar backed by an in-memory map, modeled on
+// net/http/cookiejar: cookies are matched by host/domain, path and the
+// Secure attribute, and expired cookies are never returned.
+type MemoryCookieJar struct {
+	mu      sync.Mutex
+	cookies map[string][]*Cookie // keyed by domain, without a leading dot
+}
+
+// NewMemoryCookieJar returns an empty *MemoryCookieJar.
+func NewMemoryCookieJar() *MemoryCookieJar {
+	return &MemoryCookieJar{cookies: make(map[string][]*Cookie)}
+}
+
+// SetCookies stores cookies, replacing any existing cookie with the
+// same name, domain and path.
+func (j *MemoryCookieJar) SetCookies(u *url.URL, cookies []*Cookie) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	for _, c := range cookies {
+		domain := strings.ToLower(strings.TrimPrefix(c.Domain, "."))
+		if domain == "" {
+			domain = strings.ToLower(u.Hostname())
+		}
+
+		path := c.Path
+		if path == "" {
+			path = "/"
+		}
+		// Normalize before storing so a later SetCookies call (which
+		// compares against e.Path) matches this entry instead of piling
+		// up a duplicate for every response.
+		c.Path = path
+
+		existing := j.cookies[domain]
+		replaced := false
+		for i, e := range existing {
+			if e.Name == c.Name && e.Path == path {
+				existing[i] = c
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			existing = append(existing, c)
+		}
+
+		j.cookies[domain] = existing
+	}
+}
+
+// Cookies returns the cookies that should be sent in a request to u:
+// those whose domain matches u's host (or a parent of it), whose path
+// is a prefix of u's path, that aren't expired, and that aren't marked
+// Secure when u isn't https.
+func (j *MemoryCookieJar) Cookies(u *url.URL) []*Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	host := strings.ToLower(u.Hostname())
+	secure := u.Scheme == "https"
+
+	var out []*Cookie
+	for domain, cookies := range j.cookies {
+		if !domainMatch(host, domain) {
+			continue
+		}
+
+		for _, c := range cookies {
+			if c.Secure && !secure {
+				continue
+			}
+			if !c.Expires.IsZero() && time.Now().After(c.Expires) {
+				continue
+			}
+			if !pathMatch(u.Path, c.Path) {
+				continue
+			}
+			out = append(out, c)
+		}
+	}
+
+	return out
+}
+
+// domainMatch reports whether host is domain, or a subdomain of it.
+func domainMatch(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
+
+// pathMatch reports whether cookiePath covers reqPath, per RFC 6265 5.1.4.
+func pathMatch(reqPath, cookiePath string) bool {
+	if cookiePath == "" || cookiePath == "/" || reqPath == cookiePath {
+		return true
+	}
+
+	if !strings.HasPrefix(reqPath, cookiePath) {
+		return false
+	}
+
+	return strings.HasSuffix(cookiePath, "/") || reqPath[len(cookiePath)] == '/'
+}
+
+// withCookies returns the wire bytes for r with cookies attached via a
+// Cookie header, without mutating r itself. If r already carries a
+// Cookie header (e.g. set by hand for a fuzzing workflow), the jar's
+// cookies are merged into it rather than sent as a second header.
+func withCookies(r *Request, cookies []*Cookie) string {
+	cp := *r
+
+	value := cookieHeader(cookies)
+	if existing := r.Header("Cookie"); existing != "" {
+		value = existing + "; " + value
+	}
+
+	cp.Headers = stripHeaders(append([]string{}, r.Headers...), "Cookie")
+	cp.Headers = append(cp.Headers, "Cookie: "+value)
+
+	return cp.String()
+}
+
+// cookieHeader builds the value of a Cookie: header from a set of
+// cookies, in "name=value; name2=value2" form.
+func cookieHeader(cookies []*Cookie) string {
+	parts := make([]string, len(cookies))
+	for i, c := range cookies {
+		parts[i] = c.Name + "=" + c.Value
+	}
+	return strings.Join(parts, "; ")
+}
+
+// parseSetCookies parses every Set-Cookie header found in headers into
+// a *Cookie, skipping any that can't be parsed.
+func parseSetCookies(headers []string) []*Cookie {
+	var cookies []*Cookie
+
+	for _, raw := range headerValues(headers, "Set-Cookie") {
+		if c := parseSetCookie(raw); c != nil {
+			cookies = append(cookies, c)
+		}
+	}
+
+	return cookies
+}
+
+// parseSetCookie parses a single Set-Cookie header value.
+func parseSetCookie(raw string) *Cookie {
+	attrs := strings.Split(raw, ";")
+
+	nv := strings.SplitN(strings.TrimSpace(attrs[0]), "=", 2)
+	if len(nv) != 2 {
+		return nil
+	}
+
+	c := &Cookie{Name: strings.TrimSpace(nv[0]), Value: strings.TrimSpace(nv[1])}
+
+	for _, attr := range attrs[1:] {
+		attr = strings.TrimSpace(attr)
+		kv := strings.SplitN(attr, "=", 2)
+
+		key := strings.ToLower(kv[0])
+		val := ""
+		if len(kv) == 2 {
+			val = strings.TrimSpace(kv[1])
+		}
+
+		switch key {
+		case "domain":
+			c.Domain = val
+		case "path":
+			c.Path = val
+		case "secure":
+			c.Secure = true
+		case "max-age":
+			if secs, err := strconv.Atoi(val); err == nil {
+				c.Expires = time.Now().Add(time.Duration(secs) * time.Second)
+			}
+		case "expires":
+			if t, err := time.Parse(time.RFC1123, val); err == nil {
+				c.Expires = t
+			}
+		}
+	}
+
+	return c
+}
+
+// headerValues returns the (trimmed) values of every header in headers
+// whose name matches name, case-insensitively.
+func headerValues(headers []string, name string) []string {
+	var out []string
+
+	for _, h := range headers {
+		p := strings.SplitN(h, ":", 2)
+		if len(p) != 2 {
+			continue
+		}
+
+		if strings.EqualFold(strings.TrimSpace(p[0]), name) {
+			out = append(out, strings.TrimSpace(p[1]))
+		}
+	}
+
+	return out
+}
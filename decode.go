@@ -0,0 +1,93 @@
+package rawhttp
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+)
+
+// DecodedBody returns the response body decoded according to its
+// Content-Encoding header. gzip, deflate and br are supported; stacked
+// codings (e.g. "gzip, br") are reversed in the order they were
+// applied. Body() keeps returning the still-encoded bytes (with only
+// any chunked transfer framing removed), so raw request users can still
+// see the body as the server encoded it.
+func (r Response) DecodedBody() ([]byte, error) {
+	ce := r.Header("Content-Encoding")
+	if ce == "" {
+		return r.body, nil
+	}
+
+	body := r.body
+	codings := strings.Split(ce, ",")
+
+	for i := len(codings) - 1; i >= 0; i-- {
+		coding := strings.ToLower(strings.TrimSpace(codings[i]))
+
+		var err error
+		switch coding {
+		case "gzip":
+			body, err = decodeGzip(body)
+		case "deflate":
+			body, err = decodeFlate(body)
+		case "br":
+			body, err = decodeBrotli(body)
+		case "identity", "":
+			// no-op
+		default:
+			return nil, fmt.Errorf("unsupported content-encoding: %s", coding)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return body, nil
+}
+
+func decodeGzip(body []byte) ([]byte, error) {
+	zr, err := gzip.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	return ioutil.ReadAll(zr)
+}
+
+func decodeFlate(body []byte) ([]byte, error) {
+	fr := flate.NewReader(bytes.NewReader(body))
+	defer fr.Close()
+
+	return ioutil.ReadAll(fr)
+}
+
+func decodeBrotli(body []byte) ([]byte, error) {
+	br := brotli.NewReader(bytes.NewReader(body))
+	return ioutil.ReadAll(br)
+}
+
+// ContentType returns the media type and charset parsed from the
+// response's Content-Type header. charset is "" if the header didn't
+// specify one, and both are "" if there was no Content-Type header at
+// all.
+func (r Response) ContentType() (mediaType string, charset string, err error) {
+	ct := r.Header("Content-Type")
+	if ct == "" {
+		return "", "", nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(ct)
+	if err != nil {
+		return "", "", err
+	}
+
+	return mediaType, params["charset"], nil
+}
@@ -1,7 +1,11 @@
 package rawhttp
 
 import (
+	"bufio"
+	"compress/gzip"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
@@ -75,3 +79,401 @@ func TestFromURL(t *testing.T) {
 		t.Errorf("want response header to be 'check' have '%s'", resp.Header("Response"))
 	}
 }
+
+func TestClientKeepAliveSequentialRequests(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "ok")
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	client := NewClient()
+
+	for i := 0; i < 3; i++ {
+		req := RawRequest{
+			Hostname: u.Hostname(),
+			Port:     u.Port(),
+			Request:  "GET / HTTP/1.1\r\nHost: localhost\r\n",
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request %d: want nil error, have %s", i, err)
+		}
+
+		if resp.StatusCode() != "200" {
+			t.Fatalf("request %d: want 200; have %s (status line %q)", i, resp.StatusCode(), resp.StatusLine())
+		}
+	}
+}
+
+func TestClientDoDoesNotPadRequestWithCustomEOL(t *testing.T) {
+	var sawBody string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		sawBody = string(body)
+		fmt.Fprintln(w, "ok")
+	}))
+	defer ts.Close()
+
+	req, err := FromURL("POST", ts.URL)
+	if err != nil {
+		t.Fatalf("want nil error, have %s", err)
+	}
+	req.EOL = "\n"
+	req.AutoSetHost()
+	req.Body = "some=data"
+	req.AutoSetContentLength()
+
+	resp, err := NewClient().Do(req)
+	if err != nil {
+		t.Fatalf("want nil error, have %s", err)
+	}
+
+	if resp.StatusCode() != "200" {
+		t.Fatalf("want 200; have %s", resp.StatusCode())
+	}
+
+	if sawBody != "some=data" {
+		t.Errorf("want server to see body 'some=data'; have %q", sawBody)
+	}
+}
+
+func TestDoFollowPostRedirectToGet(t *testing.T) {
+	var sawContentLength string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/target", http.StatusFound)
+			return
+		}
+
+		sawContentLength = r.Header.Get("Content-Length")
+		fmt.Fprintln(w, "ok")
+	}))
+	defer ts.Close()
+
+	req, err := FromURL("POST", ts.URL+"/redirect")
+	if err != nil {
+		t.Fatalf("want nil error, have %s", err)
+	}
+	req.AutoSetHost()
+	req.Body = "some=data"
+	req.AutoSetContentLength()
+
+	chain, err := DoFollow(req, RedirectPolicy{})
+	if err != nil {
+		t.Fatalf("want nil error, have %s", err)
+	}
+
+	if len(chain) != 2 {
+		t.Fatalf("want a 2-response chain; have %d", len(chain))
+	}
+
+	if sawContentLength != "" {
+		t.Errorf("want no stale Content-Length on the redirected GET; have %q", sawContentLength)
+	}
+
+	have := strings.TrimSpace(string(chain[len(chain)-1].Body()))
+	if have != "ok" {
+		t.Errorf("want final body to be 'ok'; have '%s'", have)
+	}
+}
+
+func TestClientDoFollowUsesClientJarAcrossRedirect(t *testing.T) {
+	var sawCookie string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.SetCookie(w, &http.Cookie{Name: "sess", Value: "abc"})
+			http.Redirect(w, r, "/target", http.StatusFound)
+			return
+		}
+
+		sawCookie = r.Header.Get("Cookie")
+		fmt.Fprintln(w, "ok")
+	}))
+	defer ts.Close()
+
+	req, err := FromURL("GET", ts.URL+"/redirect")
+	if err != nil {
+		t.Fatalf("want nil error, have %s", err)
+	}
+	req.AutoSetHost()
+
+	client := NewClient()
+	client.Jar = NewMemoryCookieJar()
+
+	chain, err := client.DoFollow(req, RedirectPolicy{})
+	if err != nil {
+		t.Fatalf("want nil error, have %s", err)
+	}
+
+	if len(chain) != 2 {
+		t.Fatalf("want a 2-response chain; have %d", len(chain))
+	}
+
+	if sawCookie != "sess=abc" {
+		t.Errorf("want the redirected request to carry the jar's cookie; have %q", sawCookie)
+	}
+}
+
+func TestMemoryCookieJarDedupesRepeatedSetCookies(t *testing.T) {
+	jar := NewMemoryCookieJar()
+	u, err := url.Parse("http://example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 3; i++ {
+		jar.SetCookies(u, []*Cookie{{Name: "sess", Value: "abc"}})
+	}
+
+	cookies := jar.Cookies(u)
+	if len(cookies) != 1 {
+		t.Fatalf("want 1 cookie after repeated identical SetCookies calls; have %d", len(cookies))
+	}
+}
+
+func TestClientDoDoesNotMutateRequestHeaders(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "sess", Value: "abc"})
+		fmt.Fprintln(w, "ok")
+	}))
+	defer ts.Close()
+
+	req, err := FromURL("GET", ts.URL)
+	if err != nil {
+		t.Fatalf("want nil error, have %s", err)
+	}
+	req.AutoSetHost()
+
+	client := NewClient()
+	client.Jar = NewMemoryCookieJar()
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.Do(req); err != nil {
+			t.Fatalf("request %d: want nil error, have %s", i, err)
+		}
+	}
+
+	cookieHeaders := 0
+	for _, h := range req.Headers {
+		if strings.HasPrefix(strings.ToLower(h), "cookie:") {
+			cookieHeaders++
+		}
+	}
+
+	if cookieHeaders != 0 {
+		t.Errorf("want Do to leave req.Headers untouched; found %d Cookie header(s): %v", cookieHeaders, req.Headers)
+	}
+}
+
+func TestClientDoMergesJarCookiesIntoExistingCookieHeader(t *testing.T) {
+	var sawCookie string
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sawCookie = r.Header.Get("Cookie")
+		fmt.Fprintln(w, "ok")
+	}))
+	defer ts.Close()
+
+	req, err := FromURL("GET", ts.URL)
+	if err != nil {
+		t.Fatalf("want nil error, have %s", err)
+	}
+	req.AutoSetHost()
+	req.AddHeader("Cookie: manual=1")
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jar := NewMemoryCookieJar()
+	jar.SetCookies(u, []*Cookie{{Name: "sess", Value: "abc"}})
+
+	client := NewClient()
+	client.Jar = jar
+
+	if _, err := client.Do(req); err != nil {
+		t.Fatalf("want nil error, have %s", err)
+	}
+
+	if sawCookie != "manual=1; sess=abc" {
+		t.Errorf("want a single merged Cookie header; have %q", sawCookie)
+	}
+}
+
+func TestResponseDecodedBodyGzip(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Header().Set("Content-Encoding", "gzip")
+
+		gz := gzip.NewWriter(w)
+		gz.Write([]byte("the response"))
+		gz.Close()
+	}))
+	defer ts.Close()
+
+	u, err := url.Parse(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := RawRequest{
+		Hostname: u.Hostname(),
+		Port:     u.Port(),
+		Request:  "GET / HTTP/1.1\r\nHost: localhost\r\n",
+	}
+
+	resp, err := Do(req)
+	if err != nil {
+		t.Fatalf("want nil error, have %s", err)
+	}
+
+	body, err := resp.DecodedBody()
+	if err != nil {
+		t.Fatalf("want nil error, have %s", err)
+	}
+
+	if string(body) != "the response" {
+		t.Errorf("want decoded body to be 'the response'; have '%s'", body)
+	}
+
+	mediaType, charset, err := resp.ContentType()
+	if err != nil {
+		t.Fatalf("want nil error, have %s", err)
+	}
+	if mediaType != "text/plain" || charset != "utf-8" {
+		t.Errorf("want media type 'text/plain' and charset 'utf-8'; have %q and %q", mediaType, charset)
+	}
+}
+
+func TestClientHTTPProxyConnect(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, "through the tunnel")
+	}))
+	defer target.Close()
+
+	targetURL, err := url.Parse(target.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	proxyLn, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer proxyLn.Close()
+
+	go runFakeConnectProxy(proxyLn, targetURL.Host)
+
+	proxyURL, err := url.Parse("http://" + proxyLn.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := FromURL("GET", target.URL)
+	if err != nil {
+		t.Fatalf("want nil error, have %s", err)
+	}
+	req.AutoSetHost()
+	req.Proxy = proxyURL
+
+	resp, err := Do(req)
+	if err != nil {
+		t.Fatalf("want nil error, have %s", err)
+	}
+
+	have := strings.TrimSpace(string(resp.Body()))
+	if have != "through the tunnel" {
+		t.Errorf("want body to be 'through the tunnel'; have '%s'", have)
+	}
+}
+
+// runFakeConnectProxy accepts a single connection on ln, handles a
+// CONNECT request by dialing targetHost, and then shuttles bytes
+// between the two until either side closes.
+func runFakeConnectProxy(ln net.Listener, targetHost string) {
+	conn, err := ln.Accept()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	br := bufio.NewReader(conn)
+
+	line, err := br.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "CONNECT") {
+		return
+	}
+
+	for {
+		l, err := br.ReadString('\n')
+		if err != nil || strings.TrimSpace(l) == "" {
+			break
+		}
+	}
+
+	if _, err := fmt.Fprint(conn, "HTTP/1.1 200 Connection Established\r\n\r\n"); err != nil {
+		return
+	}
+
+	upstream, err := net.Dial("tcp", targetHost)
+	if err != nil {
+		return
+	}
+	defer upstream.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(upstream, br)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(conn, upstream)
+		done <- struct{}{}
+	}()
+	<-done
+}
+
+func TestReadChunkedBody(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("5\r\nhello\r\n0\r\n\r\n"))
+
+	body, trailers, err := readChunkedBody(r)
+	if err != nil {
+		t.Fatalf("want nil error, have %s", err)
+	}
+
+	if string(body) != "hello" {
+		t.Errorf("want body to be 'hello'; have '%s'", body)
+	}
+
+	if len(trailers) != 0 {
+		t.Errorf("want no trailers; have %v", trailers)
+	}
+}
+
+func TestReadChunkedBodyRejectsNegativeSize(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("-1\r\nhello\r\n0\r\n\r\n"))
+
+	_, _, err := readChunkedBody(r)
+	if err == nil {
+		t.Fatal("want an error for a negative chunk size; have nil")
+	}
+}
+
+func TestReadChunkedBodyHugeSizeReturnsErrorNotPanic(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("7fffffffffffffff\r\nhello\r\n0\r\n\r\n"))
+
+	_, _, err := readChunkedBody(r)
+	if err == nil {
+		t.Fatal("want an error for a chunk size bigger than the data available; have nil")
+	}
+}
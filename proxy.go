@@ -0,0 +1,254 @@
+package rawhttp
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dialProxy opens a connection to target ("host:port") via proxy,
+// returning a net.Conn that's ready to have the request written to it
+// (for an HTTP(S) proxy, after a successful CONNECT; for a SOCKS5
+// proxy, after the handshake completes). timeout is applied to dialing
+// the proxy itself.
+func (c *Client) dialProxy(proxy *url.URL, target string, timeout time.Duration) (net.Conn, error) {
+	switch proxy.Scheme {
+	case "http", "https":
+		return c.dialHTTPProxy(proxy, target, timeout)
+	case "socks5":
+		return c.dialSOCKS5Proxy(proxy, target, timeout)
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme: %s", proxy.Scheme)
+	}
+}
+
+// dialHTTPProxy dials proxy, issues a CONNECT for target, and returns
+// the resulting tunnel once the proxy replies with a 2xx status.
+func (c *Client) dialHTTPProxy(proxy *url.URL, target string, timeout time.Duration) (net.Conn, error) {
+	d := &net.Dialer{Timeout: timeout, KeepAlive: c.keepAlive()}
+
+	var conn net.Conn
+	var err error
+
+	if proxy.Scheme == "https" {
+		roots, rerr := x509.SystemCertPool()
+		if rerr != nil {
+			return nil, rerr
+		}
+		conn, err = tls.DialWithDialer(d, "tcp", proxyHostPort(proxy), &tls.Config{RootCAs: roots})
+	} else {
+		conn, err = d.Dial("tcp", proxyHostPort(proxy))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	connectLine := fmt.Sprintf("CONNECT %s HTTP/1.1\r\nHost: %s\r\n\r\n", target, target)
+	if _, err := fmt.Fprint(conn, connectLine); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	status, err := readConnectStatus(br)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	parts := strings.SplitN(status, " ", 3)
+	if len(parts) < 2 || len(parts[1]) != 3 || parts[1][0] != '2' {
+		conn.Close()
+		return nil, fmt.Errorf("proxy CONNECT failed: %s", status)
+	}
+
+	// Anything the proxy already buffered belongs to the tunnel (e.g.
+	// the start of a TLS handshake), so reads must keep going through br.
+	return &bufferedConn{Conn: conn, r: br}, nil
+}
+
+// readConnectStatus reads a CONNECT response's status line and
+// discards its headers up to the blank line that ends them.
+func readConnectStatus(r *bufio.Reader) (string, error) {
+	status, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return "", err
+		}
+		if strings.TrimSpace(line) == "" {
+			break
+		}
+	}
+
+	return strings.TrimSpace(status), nil
+}
+
+// proxyHostPort returns the host:port to dial for proxy, applying the
+// scheme's default port if none was given.
+func proxyHostPort(proxy *url.URL) string {
+	if proxy.Port() != "" {
+		return proxy.Host
+	}
+
+	if proxy.Scheme == "https" {
+		return proxy.Hostname() + ":443"
+	}
+
+	return proxy.Hostname() + ":80"
+}
+
+// bufferedConn is a net.Conn whose Read is served from a bufio.Reader
+// that may already hold bytes read past a proxy handshake.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (b *bufferedConn) Read(p []byte) (int, error) {
+	return b.r.Read(p)
+}
+
+// dialSOCKS5Proxy dials proxy and performs a SOCKS5 (RFC 1928) CONNECT
+// handshake for target, returning the resulting tunnel.
+func (c *Client) dialSOCKS5Proxy(proxy *url.URL, target string, timeout time.Duration) (net.Conn, error) {
+	d := &net.Dialer{Timeout: timeout, KeepAlive: c.keepAlive()}
+
+	conn, err := d.Dial("tcp", proxyHostPort(proxy))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := socks5Connect(conn, proxy.User, target); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// socks5Connect negotiates authentication (none, or username/password
+// if user is set) and then asks the SOCKS5 server at the other end of
+// conn to CONNECT to target ("host:port").
+func socks5Connect(conn net.Conn, user *url.Userinfo, target string) error {
+	host, portStr, err := net.SplitHostPort(target)
+	if err != nil {
+		return err
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return err
+	}
+
+	methods := []byte{0x00}
+	if user != nil {
+		methods = []byte{0x00, 0x02}
+	}
+
+	if _, err := conn.Write(append([]byte{0x05, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+
+	methodResp := make([]byte, 2)
+	if _, err := io.ReadFull(conn, methodResp); err != nil {
+		return err
+	}
+	if methodResp[0] != 0x05 {
+		return fmt.Errorf("unexpected socks5 version: %d", methodResp[0])
+	}
+
+	switch methodResp[1] {
+	case 0x00:
+		// no authentication required
+	case 0x02:
+		if err := socks5Authenticate(conn, user); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("no acceptable socks5 authentication method")
+	}
+
+	req := []byte{0x05, 0x01, 0x00, 0x03, byte(len(host))}
+	req = append(req, host...)
+	req = append(req, byte(port>>8), byte(port))
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	return socks5ReadReply(conn)
+}
+
+// socks5Authenticate performs the username/password sub-negotiation
+// (RFC 1929).
+func socks5Authenticate(conn net.Conn, user *url.Userinfo) error {
+	if user == nil {
+		return fmt.Errorf("socks5 proxy requires authentication")
+	}
+
+	username := user.Username()
+	password, _ := user.Password()
+
+	req := []byte{0x01, byte(len(username))}
+	req = append(req, username...)
+	req = append(req, byte(len(password)))
+	req = append(req, password...)
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	resp := make([]byte, 2)
+	if _, err := io.ReadFull(conn, resp); err != nil {
+		return err
+	}
+	if resp[1] != 0x00 {
+		return fmt.Errorf("socks5 authentication failed")
+	}
+
+	return nil
+}
+
+// socks5ReadReply reads and validates a SOCKS5 CONNECT reply, consuming
+// the bound-address field that follows the fixed header.
+func socks5ReadReply(conn net.Conn) error {
+	head := make([]byte, 4)
+	if _, err := io.ReadFull(conn, head); err != nil {
+		return err
+	}
+	if head[1] != 0x00 {
+		return fmt.Errorf("socks5 connect failed: reply code %d", head[1])
+	}
+
+	var addrLen int
+	switch head[3] {
+	case 0x01: // IPv4
+		addrLen = net.IPv4len
+	case 0x04: // IPv6
+		addrLen = net.IPv6len
+	case 0x03: // domain name
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(conn, l); err != nil {
+			return err
+		}
+		addrLen = int(l[0])
+	default:
+		return fmt.Errorf("unknown socks5 address type: %d", head[3])
+	}
+
+	// bound address + port
+	_, err := io.ReadFull(conn, make([]byte, addrLen+2))
+	return err
+}
@@ -0,0 +1,172 @@
+package rawhttp
+
+import (
+	"net/url"
+	"strings"
+)
+
+// RedirectPolicy controls how DoFollow walks a chain of redirects.
+type RedirectPolicy struct {
+	// MaxHops is the maximum number of redirects to follow before
+	// giving up. The zero value means 10.
+	MaxHops int
+
+	// StripAuthOnCrossHost, if true, removes Authorization and Cookie
+	// headers from the rebuilt request whenever a redirect points at a
+	// different host than the previous hop.
+	StripAuthOnCrossHost bool
+
+	// RewriteURL, if set, is called with the resolved redirect URL
+	// before the next request is built, letting callers redirect
+	// requests somewhere other than where the server sent them (e.g.
+	// to replay a redirect chain against a staging host).
+	RewriteURL func(u *url.URL) *url.URL
+}
+
+// maxHops returns p.MaxHops, or a default of 10 if it's unset.
+func (p RedirectPolicy) maxHops() int {
+	if p.MaxHops > 0 {
+		return p.MaxHops
+	}
+	return 10
+}
+
+// DoFollow performs req using DefaultClient and walks any redirect
+// chain it produces. It's a thin wrapper around (*Client).DoFollow,
+// kept for backward compatibility.
+func DoFollow(req Requester, policy RedirectPolicy) ([]*Response, error) {
+	return DefaultClient.DoFollow(req, policy)
+}
+
+// DoFollow performs req using c and, for each 3xx response, rebuilds a
+// new request for the resolved Location and performs that too, up to
+// policy's hop limit. It returns every response in the chain, including
+// the final non-redirect response (or the last one fetched if the hop
+// limit is hit), and any error that occured along the way. Using c
+// (rather than the package-level Do/DefaultClient) means the chain
+// picks up c's own Jar, Proxy and TLSConfig at every hop.
+func (c *Client) DoFollow(req Requester, policy RedirectPolicy) ([]*Response, error) {
+	var chain []*Response
+
+	current := req
+	for hop := 0; ; hop++ {
+		resp, err := c.Do(current)
+		if err != nil {
+			return chain, err
+		}
+		chain = append(chain, resp)
+
+		if !isRedirect(resp.StatusCode()) {
+			return chain, nil
+		}
+
+		if hop >= policy.maxHops() {
+			return chain, nil
+		}
+
+		next, err := nextRequest(current, resp, policy)
+		if err != nil {
+			return chain, err
+		}
+		if next == nil {
+			return chain, nil
+		}
+
+		current = next
+	}
+}
+
+// isRedirect reports whether code is a 3xx status code.
+func isRedirect(code string) bool {
+	return len(code) == 3 && code[0] == '3'
+}
+
+// nextRequest builds the *Request for the next hop of a redirect chain,
+// given the request and response that produced it. It returns a nil
+// request (and nil error) if there's no Location to follow.
+func nextRequest(prev Requester, resp *Response, policy RedirectPolicy) (*Request, error) {
+	prevReq, ok := prev.(*Request)
+	if !ok {
+		// We need a *Request to know the scheme/host for resolving a
+		// relative Location; anything else (e.g. a RawRequest) can't
+		// be followed.
+		return nil, nil
+	}
+
+	loc := resp.ParseLocation(prevReq)
+	if loc == "" {
+		return nil, nil
+	}
+
+	u, err := url.Parse(loc)
+	if err != nil {
+		return nil, err
+	}
+
+	if policy.RewriteURL != nil {
+		u = policy.RewriteURL(u)
+	}
+
+	method := redirectMethod(prevReq.Method, resp.StatusCode())
+
+	next, err := FromURL(method, u.String())
+	if err != nil {
+		return nil, err
+	}
+
+	next.Headers = append([]string{}, prevReq.Headers...)
+	next.Timeout = prevReq.Timeout
+
+	if method == prevReq.Method {
+		next.Body = prevReq.Body
+	} else {
+		// The body isn't carried over (e.g. a POST->GET downgrade), so
+		// the headers describing it would be stale and leave the server
+		// waiting for a body that's never coming.
+		next.Headers = stripHeaders(next.Headers, "Content-Length", "Content-Type")
+	}
+
+	if policy.StripAuthOnCrossHost && !strings.EqualFold(next.Hostname, prevReq.Hostname) {
+		next.Headers = stripHeaders(next.Headers, "Authorization", "Cookie")
+	}
+
+	return next, nil
+}
+
+// redirectMethod applies the standard 3xx method-preservation rules:
+// POST is downgraded to GET on 301, 302 and 303, but every other method
+// (and every method on 307/308) is preserved.
+func redirectMethod(method, statusCode string) string {
+	switch statusCode {
+	case "301", "302", "303":
+		if method == "POST" {
+			return "GET"
+		}
+	}
+	return method
+}
+
+// stripHeaders returns headers with any header whose name matches one
+// of names (case-insensitively) removed.
+func stripHeaders(headers []string, names ...string) []string {
+	var out []string
+
+	for _, h := range headers {
+		p := strings.SplitN(h, ":", 2)
+		name := strings.TrimSpace(p[0])
+
+		stripped := false
+		for _, n := range names {
+			if strings.EqualFold(name, n) {
+				stripped = true
+				break
+			}
+		}
+
+		if !stripped {
+			out = append(out, h)
+		}
+	}
+
+	return out
+}
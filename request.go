@@ -3,10 +3,7 @@ package rawhttp
 import (
 	"bytes"
 	"crypto/tls"
-	"crypto/x509"
 	"fmt"
-	"io"
-	"net"
 	"net/url"
 	"strings"
 	"time"
@@ -73,6 +70,17 @@ type Request struct {
 
 	// Deadline
 	Timeout time.Duration
+
+	// TLSConfig, if set, overrides the default TLS configuration used
+	// when TLS is true. A nil TLSConfig falls back to the Client's
+	// TLSConfig, and if that is nil too, to the system root pool with
+	// certificate verification disabled.
+	TLSConfig *tls.Config
+
+	// Proxy, if set, routes the connection through a proxy before
+	// talking to Host(). Supported schemes are "http", "https" and
+	// "socks5".
+	Proxy *url.URL
 }
 
 // FromURL returns a *Request for a given method and URL and any
@@ -232,6 +240,16 @@ func (r Request) GetTimeout() time.Duration {
 	return r.Timeout
 }
 
+// GetTLSConfig returns the request's TLS configuration override, if any.
+func (r Request) GetTLSConfig() *tls.Config {
+	return r.TLSConfig
+}
+
+// GetProxy returns the proxy to use for the request, if any.
+func (r Request) GetProxy() *url.URL {
+	return r.Proxy
+}
+
 // RawRequest is the most basic implementation of Requester. You should
 // probably only use it if you're doing something *really* weird
 type RawRequest struct {
@@ -277,37 +295,8 @@ func (r RawRequest) GetTimeout() time.Duration {
 }
 
 // Do performs the HTTP request for the given Requester and returns
-// a *Response and any error that occured
+// a *Response and any error that occured. It's a thin wrapper around
+// DefaultClient.Do, kept for backward compatibility.
 func Do(req Requester) (*Response, error) {
-	var conn io.ReadWriter
-	var connerr error
-
-	// This needs timeouts because it's fairly likely
-	// that something will go wrong :)
-	if req.IsTLS() {
-		roots, err := x509.SystemCertPool()
-		if err != nil {
-			return nil, err
-		}
-
-		// This library is meant for doing stupid stuff, so skipping cert
-		// verification is actually the right thing to do
-		conf := &tls.Config{RootCAs: roots, InsecureSkipVerify: true}
-		conn, connerr = tls.DialWithDialer(&net.Dialer{
-			Timeout: req.GetTimeout(),
-		}, "tcp", req.Host(), conf)
-
-	} else {
-		d := net.Dialer{Timeout: req.GetTimeout()}
-		conn, connerr = d.Dial("tcp", req.Host())
-	}
-
-	if connerr != nil {
-		return nil, connerr
-	}
-
-	fmt.Fprint(conn, req.String())
-	fmt.Fprint(conn, "\r\n")
-
-	return newResponse(conn)
+	return DefaultClient.Do(req)
 }
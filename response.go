@@ -2,6 +2,8 @@ package rawhttp
 
 import (
 	"bufio"
+	"bytes"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"strconv"
@@ -13,6 +15,7 @@ type Response struct {
 	rawStatus string
 	headers   []string
 	body      []byte
+	trailers  []string
 }
 
 // Header finds and returns the value of a header on the response.
@@ -49,7 +52,7 @@ func (r Response) ParseLocation(req *Request) string {
 	}
 
 	if len(loc) > 0 && loc[0] == '/' {
-		return req.Scheme + "://" + req.Hostname + loc
+		return req.Scheme + "://" + req.Host() + loc
 	}
 
 	return loc
@@ -80,11 +83,98 @@ func (r Response) Body() []byte {
 	return r.body
 }
 
+// Trailers returns the trailer headers sent after a chunked response
+// body. It is empty unless the response was chunked and the server
+// actually sent trailers.
+func (r Response) Trailers() []string {
+	return r.trailers
+}
+
 // addHeader adds a header to the *Response
 func (r *Response) addHeader(header string) {
 	r.headers = append(r.headers, header)
 }
 
+// addTrailer adds a trailer header to the *Response
+func (r *Response) addTrailer(trailer string) {
+	r.trailers = append(r.trailers, trailer)
+}
+
+// isChunked returns true if the last coding in a Transfer-Encoding
+// header is "chunked", per RFC 7230's "last coding wins" rule.
+func isChunked(transferEncoding string) bool {
+	if transferEncoding == "" {
+		return false
+	}
+
+	codings := strings.Split(transferEncoding, ",")
+	last := strings.ToLower(strings.TrimSpace(codings[len(codings)-1]))
+
+	return last == "chunked"
+}
+
+// readChunkedBody reads a chunked-transfer-encoded body from r, stopping
+// at the zero-length chunk, then consumes any trailer headers up to the
+// empty line that ends them. It returns the dechunked body and any
+// trailers that were sent.
+func readChunkedBody(r *bufio.Reader) ([]byte, []string, error) {
+	var body bytes.Buffer
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, nil, err
+		}
+
+		line = strings.TrimSpace(line)
+
+		// Strip any chunk extensions. E.g: 1a;foo=bar
+		if i := strings.IndexByte(line, ';'); i != -1 {
+			line = line[:i]
+		}
+
+		// ParseUint (rather than ParseInt) rejects a leading '-', so a
+		// malformed negative chunk size is caught here instead of being
+		// passed to make() below.
+		size, err := strconv.ParseUint(line, 16, 63)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid chunk size: %s", line)
+		}
+
+		if size == 0 {
+			break
+		}
+
+		// CopyN reads size bytes into body without allocating a
+		// size-length buffer up front, so a huge (but otherwise
+		// well-formed) chunk-size line can't be used to make the
+		// process OOM or panic before a single byte has even been
+		// read off the wire.
+		if _, err := io.CopyN(&body, r, int64(size)); err != nil {
+			return nil, nil, fmt.Errorf("short read on chunk body: %s", err)
+		}
+
+		// Consume the CRLF that follows the chunk data
+		if _, err := r.ReadString('\n'); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var trailers []string
+	for {
+		line, err := r.ReadString('\n')
+		line = strings.TrimSpace(line)
+
+		if err != nil || line == "" {
+			break
+		}
+
+		trailers = append(trailers, line)
+	}
+
+	return body.Bytes(), trailers, nil
+}
+
 // newResponse accepts an io.Reader, reads the response
 // headers and body and returns a new *Response and any
 // error that occured.
@@ -110,9 +200,19 @@ func newResponse(conn io.Reader) (*Response, error) {
 		resp.addHeader(line)
 	}
 
-	if cl := resp.Header("Content-Length"); cl != "" {
-		length, err := strconv.Atoi(cl)
+	cl := resp.Header("Content-Length")
+
+	switch {
+	case cl == "" && isChunked(resp.Header("Transfer-Encoding")):
+		body, trailers, err := readChunkedBody(r)
+		if err != nil {
+			return nil, err
+		}
+		resp.body = body
+		resp.trailers = trailers
 
+	case cl != "":
+		length, err := strconv.Atoi(cl)
 		if err != nil {
 			return nil, err
 		}
@@ -126,7 +226,7 @@ func newResponse(conn io.Reader) (*Response, error) {
 			resp.body = b
 		}
 
-	} else {
+	default:
 		b, err := ioutil.ReadAll(r)
 		if err != nil {
 			return nil, err